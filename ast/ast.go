@@ -0,0 +1,23 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+// Package ast defines the minimal parse-tree and token types shared by
+// every parser the gen package emits, so generated files stay small
+// and self-contained instead of each declaring their own copy.
+package ast
+
+// Token is a single lexical token as produced by a Lexer. Sym must
+// match one of the grammar's terminal symbols, or "$" for end of
+// input.
+type Token struct {
+	Sym string // the terminal symbol this token satisfies
+	Lit string // the token's literal text, if any
+}
+
+// Node is a generic parse-tree node. Leaf nodes (one per matched
+// terminal) carry the token that produced them; interior nodes carry
+// the children matched by their production, in production order.
+type Node struct {
+	Sym      string  // the grammar symbol this node derives from
+	Tok      Token   // the token consumed here, for leaf nodes
+	Children []*Node // the children matched by this production
+}
@@ -0,0 +1,755 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+// Package combinator is a typed, composable alternative to the
+// grammar package's Node/Header graph: instead of describing a
+// grammar and feeding text through Parse, callers build a Symbol[T]
+// tree by hand out of small combinators (FirstOf, Seq, ZeroOrMore,
+// Prefixed, Suffixed, RuneFunc) and call Decode directly. SymbolPtr
+// supplies the forward references a recursive grammar needs, the way
+// a Header supplies them on the graph side.
+//
+// The two views are kept in sync by FromGraph, which builds a
+// Symbol[T] tree from an existing *Header so a hand-written parser can
+// reuse a grammar built (or parsed) on the graph side, and ToGraph,
+// which reflects a combinator tree built by this package's own
+// constructors back into a *Header so First/Follow/ll1.BuildLL1 still
+// apply to it. A Symbol assembled some other way - a bare func literal
+// satisfying the interface - carries no structure for ToGraph to walk,
+// so ToGraph reports that rather than guessing at its shape.
+package combinator
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/mdhender/fluffy-giggle/grammar"
+)
+
+// Reader is the input a Symbol decodes from. It is passed around as an
+// interface over a stateful cursor (rather than a value the caller
+// must thread back through) so a combinator that fails partway through
+// can snapshot with Mark and back out with Reset, and so Decode need
+// only return the value it produced.
+type Reader interface {
+	// Peek returns the next rune without consuming it, and false at
+	// end of input.
+	Peek() (rune, bool)
+	// Next consumes and returns the next rune, and false at end of
+	// input.
+	Next() (rune, bool)
+	// Mark returns an opaque checkpoint Reset can rewind to.
+	Mark() int
+	// Reset rewinds to a checkpoint previously returned by Mark.
+	Reset(mark int)
+}
+
+// NewReader returns a Reader over input, suitable for driving a
+// Symbol's Decode.
+func NewReader(input string) Reader {
+	return &runeReader{runes: []rune(input)}
+}
+
+type runeReader struct {
+	runes []rune
+	pos   int
+}
+
+func (r *runeReader) Peek() (rune, bool) {
+	if r.pos >= len(r.runes) {
+		return 0, false
+	}
+	return r.runes[r.pos], true
+}
+
+func (r *runeReader) Next() (rune, bool) {
+	ch, ok := r.Peek()
+	if ok {
+		r.pos++
+	}
+	return ch, ok
+}
+
+func (r *runeReader) Mark() int { return r.pos }
+
+func (r *runeReader) Reset(mark int) { r.pos = mark }
+
+// Symbol is a typed, composable parser: Decode reads whatever it
+// needs from r and returns the value it produced, or an error if r
+// didn't hold a match. A failed Decode must not be assumed to have
+// left r's cursor where it found it; callers that want to try an
+// alternative must Mark before calling Decode and Reset on failure,
+// which is exactly what FirstOf and ZeroOrMore do internally.
+type Symbol[T any] interface {
+	Decode(r Reader) (T, error)
+}
+
+// graphNode is implemented by every Symbol this package's constructors
+// return, letting ToGraph reflect a combinator tree back into the
+// grammar package's analyzable Node/Header form. It mirrors the
+// first/tails shape grammar.parser's term and factor methods return:
+// the entry node of whatever was built, and the dangling nodes whose
+// Suc the caller still needs to patch.
+type graphNode interface {
+	toNode(ctx *toGraphCtx) (first *grammar.Node, tails []*grammar.Node)
+}
+
+type toGraphCtx struct {
+	// headers caches the *Header built for each *SymbolPtr[T] seen so
+	// far, keyed by the pointer itself (comparable regardless of T),
+	// so a recursive reference reuses the same Header instead of
+	// expanding forever.
+	headers map[any]*grammar.Header
+	err     error
+}
+
+// RuneFunc returns a Symbol matching a single rune satisfying pred.
+// label names the rune class for error messages and, if the Symbol is
+// later passed to ToGraph, becomes the reflected terminal's Tsym - so
+// First/Follow treat every rune pred accepts as the one opaque
+// terminal label, not as the individual characters it matches.
+func RuneFunc(label string, pred func(rune) bool) Symbol[rune] {
+	return &runeFunc{label: label, pred: pred}
+}
+
+// Literal returns a Symbol matching exactly ch. It is RuneFunc with a
+// label equal to the character itself, so a Literal reflects through
+// ToGraph as the same single-character Tsym grammar.Parse produces
+// for a terminal in EBNF source.
+func Literal(ch rune) Symbol[rune] {
+	c := ch
+	return RuneFunc(string(c), func(r rune) bool { return r == c })
+}
+
+type runeFunc struct {
+	label string
+	pred  func(rune) bool
+}
+
+func (f *runeFunc) Decode(r Reader) (rune, error) {
+	ch, ok := r.Peek()
+	if !ok {
+		return 0, fmt.Errorf("combinator: expected %s, got end of input", f.label)
+	}
+	if !f.pred(ch) {
+		return 0, fmt.Errorf("combinator: expected %s, got %s", f.label, strconv.QuoteRune(ch))
+	}
+	r.Next()
+	return ch, nil
+}
+
+func (f *runeFunc) toNode(*toGraphCtx) (*grammar.Node, []*grammar.Node) {
+	n := &grammar.Node{Terminal: true, Tsym: f.label}
+	return n, []*grammar.Node{n}
+}
+
+// FirstOf returns a Symbol trying each of syms in order, backtracking
+// between attempts, and succeeding with the first one that matches.
+func FirstOf[T any](syms ...Symbol[T]) Symbol[T] {
+	return &firstOf[T]{syms: syms}
+}
+
+type firstOf[T any] struct {
+	syms []Symbol[T]
+}
+
+func (f *firstOf[T]) Decode(r Reader) (T, error) {
+	var zero T
+	var errs []error
+	for _, s := range f.syms {
+		mark := r.Mark()
+		v, err := s.Decode(r)
+		if err == nil {
+			return v, nil
+		}
+		r.Reset(mark)
+		errs = append(errs, err)
+	}
+	return zero, fmt.Errorf("combinator: FirstOf: no alternative matched: %w", errors.Join(errs...))
+}
+
+func (f *firstOf[T]) toNode(ctx *toGraphCtx) (*grammar.Node, []*grammar.Node) {
+	var first, last *grammar.Node
+	var tails []*grammar.Node
+	for _, s := range f.syms {
+		gn, ok := s.(graphNode)
+		if !ok {
+			ctx.err = fmt.Errorf("combinator: ToGraph: FirstOf alternative not built by this package's constructors")
+			continue
+		}
+		n, t := gn.toNode(ctx)
+		if first == nil {
+			first = n
+		} else {
+			end := last
+			for end.Alt != nil {
+				end = end.Alt
+			}
+			end.Alt = n
+		}
+		last = n
+		tails = append(tails, t...)
+	}
+	return first, tails
+}
+
+// Seq returns a Symbol matching syms in order and collecting their
+// values, failing as soon as one of them does.
+func Seq[T any](syms ...Symbol[T]) Symbol[[]T] {
+	return &seqSym[T]{syms: syms}
+}
+
+type seqSym[T any] struct {
+	syms []Symbol[T]
+}
+
+func (s *seqSym[T]) Decode(r Reader) ([]T, error) {
+	out := make([]T, 0, len(s.syms))
+	for _, sym := range s.syms {
+		v, err := sym.Decode(r)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+	}
+	return out, nil
+}
+
+func (s *seqSym[T]) toNode(ctx *toGraphCtx) (*grammar.Node, []*grammar.Node) {
+	var first *grammar.Node
+	var tails []*grammar.Node
+	for _, sym := range s.syms {
+		gn, ok := sym.(graphNode)
+		if !ok {
+			ctx.err = fmt.Errorf("combinator: ToGraph: Seq element not built by this package's constructors")
+			continue
+		}
+		n, t := gn.toNode(ctx)
+		if first == nil {
+			first = n
+		} else {
+			for _, tail := range tails {
+				tail.Suc = n
+			}
+		}
+		tails = t
+	}
+	return first, tails
+}
+
+// ZeroOrMore returns a Symbol matching sym zero or more times,
+// collecting its values, backtracking off the attempt that finally
+// fails rather than letting it consume input.
+func ZeroOrMore[T any](sym Symbol[T]) Symbol[[]T] {
+	return &zeroOrMore[T]{sym: sym}
+}
+
+type zeroOrMore[T any] struct {
+	sym Symbol[T]
+}
+
+func (z *zeroOrMore[T]) Decode(r Reader) ([]T, error) {
+	var out []T
+	for {
+		mark := r.Mark()
+		v, err := z.sym.Decode(r)
+		if err != nil {
+			r.Reset(mark)
+			return out, nil
+		}
+		out = append(out, v)
+	}
+}
+
+// toNode builds exactly the `s.Suc = s; s.Alt = empty` shape
+// grammar/parser.go's primary method builds for a `[...]` group: the
+// body loops back to its own entry on every dangling end, with an
+// epsilon alternative appended to the body's Alt chain as the
+// zero-occurrence exit.
+func (z *zeroOrMore[T]) toNode(ctx *toGraphCtx) (*grammar.Node, []*grammar.Node) {
+	gn, ok := z.sym.(graphNode)
+	if !ok {
+		ctx.err = fmt.Errorf("combinator: ToGraph: ZeroOrMore body not built by this package's constructors")
+		n := &grammar.Node{Terminal: true, Tsym: `ε`}
+		return n, []*grammar.Node{n}
+	}
+	bodyFirst, bodyTails := gn.toNode(ctx)
+
+	empty := &grammar.Node{Terminal: true, Tsym: `ε`}
+	last := bodyFirst
+	for last.Alt != nil {
+		last = last.Alt
+	}
+	last.Alt = empty
+	for _, t := range bodyTails {
+		t.Suc = bodyFirst
+	}
+	return bodyFirst, []*grammar.Node{empty}
+}
+
+// Prefixed returns a Symbol matching prefix then sym, discarding
+// prefix's value and returning sym's.
+func Prefixed[P, T any](prefix Symbol[P], sym Symbol[T]) Symbol[T] {
+	return &prefixed[P, T]{prefix: prefix, sym: sym}
+}
+
+type prefixed[P, T any] struct {
+	prefix Symbol[P]
+	sym    Symbol[T]
+}
+
+func (p *prefixed[P, T]) Decode(r Reader) (T, error) {
+	var zero T
+	if _, err := p.prefix.Decode(r); err != nil {
+		return zero, err
+	}
+	return p.sym.Decode(r)
+}
+
+func (p *prefixed[P, T]) toNode(ctx *toGraphCtx) (*grammar.Node, []*grammar.Node) {
+	pgn, ok := p.prefix.(graphNode)
+	if !ok {
+		ctx.err = fmt.Errorf("combinator: ToGraph: Prefixed prefix not built by this package's constructors")
+		return nil, nil
+	}
+	sgn, ok := p.sym.(graphNode)
+	if !ok {
+		ctx.err = fmt.Errorf("combinator: ToGraph: Prefixed symbol not built by this package's constructors")
+		return nil, nil
+	}
+	first, tails := pgn.toNode(ctx)
+	sFirst, sTails := sgn.toNode(ctx)
+	for _, t := range tails {
+		t.Suc = sFirst
+	}
+	return first, sTails
+}
+
+// Suffixed returns a Symbol matching sym then suffix, discarding
+// suffix's value and returning sym's.
+func Suffixed[T, S any](sym Symbol[T], suffix Symbol[S]) Symbol[T] {
+	return &suffixed[T, S]{sym: sym, suffix: suffix}
+}
+
+type suffixed[T, S any] struct {
+	sym    Symbol[T]
+	suffix Symbol[S]
+}
+
+func (s *suffixed[T, S]) Decode(r Reader) (T, error) {
+	var zero T
+	v, err := s.sym.Decode(r)
+	if err != nil {
+		return zero, err
+	}
+	if _, err := s.suffix.Decode(r); err != nil {
+		return zero, err
+	}
+	return v, nil
+}
+
+func (s *suffixed[T, S]) toNode(ctx *toGraphCtx) (*grammar.Node, []*grammar.Node) {
+	sgn, ok := s.sym.(graphNode)
+	if !ok {
+		ctx.err = fmt.Errorf("combinator: ToGraph: Suffixed symbol not built by this package's constructors")
+		return nil, nil
+	}
+	xgn, ok := s.suffix.(graphNode)
+	if !ok {
+		ctx.err = fmt.Errorf("combinator: ToGraph: Suffixed suffix not built by this package's constructors")
+		return nil, nil
+	}
+	first, tails := sgn.toNode(ctx)
+	xFirst, xTails := xgn.toNode(ctx)
+	for _, t := range tails {
+		t.Suc = xFirst
+	}
+	return first, xTails
+}
+
+// SymbolPtr is a settable forward reference: declare one with
+// NewSymbolPtr before the productions that mention it, pass it around
+// as the Symbol those productions need, and assign its Sym field once
+// the real symbol exists. This is the combinator-side equivalent of a
+// Header, which is why ToGraph reflects a SymbolPtr back into one.
+type SymbolPtr[T any] struct {
+	Sym  Symbol[T]
+	name string
+}
+
+// NewSymbolPtr returns an empty SymbolPtr named name. name labels the
+// non-terminal when the combinator tree is reflected back into a
+// *grammar.Header via ToGraph.
+func NewSymbolPtr[T any](name string) *SymbolPtr[T] {
+	return &SymbolPtr[T]{name: name}
+}
+
+func (p *SymbolPtr[T]) Decode(r Reader) (T, error) {
+	if p.Sym == nil {
+		var zero T
+		return zero, fmt.Errorf("combinator: SymbolPtr %q used before its Sym field was assigned", p.name)
+	}
+	return p.Sym.Decode(r)
+}
+
+func (p *SymbolPtr[T]) toNode(ctx *toGraphCtx) (*grammar.Node, []*grammar.Node) {
+	h, ok := ctx.headers[p]
+	if !ok {
+		h = &grammar.Header{Sym: p.name}
+		ctx.headers[p] = h
+		gn, ok := p.Sym.(graphNode)
+		if !ok {
+			ctx.err = fmt.Errorf("combinator: ToGraph: SymbolPtr %q wraps a Symbol not built by this package's constructors", p.name)
+		} else {
+			h.Entry, _ = gn.toNode(ctx)
+		}
+	}
+	n := &grammar.Node{Nsym: h}
+	return n, []*grammar.Node{n}
+}
+
+// ToGraph reflects a combinator tree built by this package's own
+// constructors back into the grammar package's Node/Header form, so
+// First, Follow, and ll1.BuildLL1 can analyze it. It fails if sym, or
+// anything reachable from it, wasn't built by one of this package's
+// constructors - there's nothing to walk in an arbitrary Decode func.
+func ToGraph[T any](sym Symbol[T]) (*grammar.Header, error) {
+	ctx := &toGraphCtx{headers: map[any]*grammar.Header{}}
+
+	if ptr, ok := sym.(*SymbolPtr[T]); ok {
+		ptr.toNode(ctx)
+		if ctx.err != nil {
+			return nil, ctx.err
+		}
+		return ctx.headers[ptr], nil
+	}
+
+	gn, ok := sym.(graphNode)
+	if !ok {
+		return nil, fmt.Errorf("combinator: ToGraph: Symbol not built by this package's constructors cannot be reflected")
+	}
+	entry, _ := gn.toNode(ctx)
+	if ctx.err != nil {
+		return nil, ctx.err
+	}
+	return &grammar.Header{Sym: "S", Entry: entry}, nil
+}
+
+// FromGraph builds a Symbol[T] tree from the grammar rooted at start,
+// so a hand-written parser can execute a grammar built (or parsed)
+// on the Node/Header side without running it through gen. Every
+// terminal is matched and discarded; every non-terminal recurses
+// through a SymbolPtr (so a recursive grammar doesn't build forever)
+// and the resulting production's children - one per non-terminal
+// reference in its right-hand side, in order - are reduced to a T by
+// actions[sym], or, absent an entry for sym, by returning the single
+// child if there is exactly one and the zero value of T otherwise.
+func FromGraph[T any](start *grammar.Header, actions map[string]func(children []T) T) (Symbol[T], error) {
+	if start == nil {
+		return nil, fmt.Errorf("combinator: FromGraph: nil start symbol")
+	}
+	b := &fromGraphBuilder[T]{actions: actions, ptrs: map[*grammar.Header]*SymbolPtr[T]{}}
+	return b.build(start)
+}
+
+type fromGraphBuilder[T any] struct {
+	actions map[string]func([]T) T
+	ptrs    map[*grammar.Header]*SymbolPtr[T]
+}
+
+func (b *fromGraphBuilder[T]) build(h *grammar.Header) (Symbol[T], error) {
+	if ptr, ok := b.ptrs[h]; ok {
+		return ptr, nil
+	}
+	ptr := NewSymbolPtr[T](h.Sym)
+	b.ptrs[h] = ptr
+
+	seq, err := b.buildSequence(h.Entry, nil)
+	if err != nil {
+		return nil, err
+	}
+	ptr.Sym = &reduceSymbol[T]{seq: seq, action: b.actions[h.Sym]}
+	return ptr, nil
+}
+
+// buildSequence, buildAlternative, buildChoice, and buildLoop mirror
+// gen.emitSequence/emitAlternative/emitChoice/emitLoop's traversal of
+// the same Suc/Alt/loopback shape; where gen emits Go source for a
+// step, these build the equivalent Symbol[[]T]. The loop check comes
+// before the choice check: a `[...]` loop's entry also carries a
+// non-nil Alt (its own epsilon exit), which buildChoice would
+// otherwise mistake for a sibling alternative of some enclosing
+// choice instead of the loop's own bypass.
+func (b *fromGraphBuilder[T]) buildSequence(node, loopback *grammar.Node) (Symbol[[]T], error) {
+	if node == nil || node == loopback {
+		return epsilonSymbol[[]T]{}, nil
+	}
+	if grammar.HasBackEdgeToSelf(node) {
+		return b.buildLoop(node, loopback)
+	}
+	if node.Alt != nil {
+		return b.buildChoice(node, loopback)
+	}
+	return b.buildAlternative(node, loopback)
+}
+
+func (b *fromGraphBuilder[T]) buildAlternative(node, loopback *grammar.Node) (Symbol[[]T], error) {
+	if node == nil || node == loopback {
+		return epsilonSymbol[[]T]{}, nil
+	}
+	if grammar.HasBackEdgeToSelf(node) {
+		return b.buildLoop(node, loopback)
+	}
+	step, err := b.buildStep(node)
+	if err != nil {
+		return nil, err
+	}
+	rest, err := b.buildSequence(node.Suc, loopback)
+	if err != nil {
+		return nil, err
+	}
+	return &concatList[T]{a: step, b: rest}, nil
+}
+
+func (b *fromGraphBuilder[T]) buildChoice(node, loopback *grammar.Node) (Symbol[[]T], error) {
+	var alts []Symbol[[]T]
+	for n := node; n != nil; n = n.Alt {
+		alt, err := b.buildAlternative(n, loopback)
+		if err != nil {
+			return nil, err
+		}
+		alts = append(alts, alt)
+	}
+	return FirstOf[[]T](alts...), nil
+}
+
+// buildLoop builds a Symbol repeating entry's real alternative(s) via
+// ZeroOrMore, which already provides "zero or more" - the last node
+// of entry's Alt chain is always the epsilon alternative the `[...]`
+// construction appends, the "zero occurrences" exit, and isn't itself
+// part of what repeats. Everything before it is real, even a node
+// that is itself epsilon-tagged: that happens when entry's body is
+// itself a nested loop, whose own epsilon exit still has a non-nil
+// Suc (the content required after that inner loop stops), so it must
+// be walked rather than discarded the way the true trailing exit is.
+// Each alternative is unrolled by buildLoopAlternative rather than
+// buildAlternative: every node inside the cycle trivially "has a back
+// edge to itself" once you're already inside it (cycle membership is
+// symmetric), so re-running that check here would treat the body as
+// an infinitely nested loop instead of walking it once per iteration.
+func (b *fromGraphBuilder[T]) buildLoop(entry, loopback *grammar.Node) (Symbol[[]T], error) {
+	var reals []*grammar.Node
+	exit := entry
+	for exit.Alt != nil {
+		reals = append(reals, exit)
+		exit = exit.Alt
+	}
+	if len(reals) == 0 {
+		return nil, fmt.Errorf("combinator: FromGraph: loop has no non-epsilon alternative")
+	}
+
+	var body Symbol[[]T]
+	if len(reals) == 1 {
+		b1, err := b.buildLoopAlternative(reals[0], entry)
+		if err != nil {
+			return nil, err
+		}
+		body = b1
+	} else {
+		var alts []Symbol[[]T]
+		for _, alt := range reals {
+			a, err := b.buildLoopAlternative(alt, entry)
+			if err != nil {
+				return nil, err
+			}
+			alts = append(alts, a)
+		}
+		body = FirstOf[[]T](alts...)
+	}
+
+	// exit is the last node of entry's Alt chain: the epsilon
+	// alternative appended when the loop was built. Whatever follows
+	// the loop was linked to its Suc.
+	rest, err := b.buildSequence(exit.Suc, loopback)
+	if err != nil {
+		return nil, err
+	}
+
+	return &concatList[T]{a: &flattenList[T]{sym: ZeroOrMore[[]T](body)}, b: rest}, nil
+}
+
+// buildLoopAlternative builds one pass through a loop body starting at
+// node, stopping exactly at entry (where the body's own Suc chain
+// cycles back to repeat).
+func (b *fromGraphBuilder[T]) buildLoopAlternative(node, entry *grammar.Node) (Symbol[[]T], error) {
+	if node == nil {
+		return epsilonSymbol[[]T]{}, nil
+	}
+	step, err := b.buildStep(node)
+	if err != nil {
+		return nil, err
+	}
+	if node.Suc == nil || node.Suc == entry {
+		return step, nil
+	}
+	rest, err := b.buildLoopAlternative(node.Suc, entry)
+	if err != nil {
+		return nil, err
+	}
+	return &concatList[T]{a: step, b: rest}, nil
+}
+
+func (b *fromGraphBuilder[T]) buildStep(n *grammar.Node) (Symbol[[]T], error) {
+	if n.Terminal {
+		if n.Tsym == `ε` {
+			return epsilonSymbol[[]T]{}, nil
+		}
+		runes := []rune(n.Tsym)
+		if len(runes) != 1 {
+			return nil, fmt.Errorf("combinator: FromGraph: multi-rune terminal %q not supported", n.Tsym)
+		}
+		return &matchOnly[T]{sym: Literal(runes[0])}, nil
+	}
+	sym, err := b.build(n.Nsym)
+	if err != nil {
+		return nil, err
+	}
+	return &singleValue[T]{sym: sym}, nil
+}
+
+// epsilonSymbol always succeeds without consuming input, returning
+// the zero value of T.
+type epsilonSymbol[T any] struct{}
+
+func (epsilonSymbol[T]) Decode(Reader) (T, error) {
+	var zero T
+	return zero, nil
+}
+
+func (epsilonSymbol[T]) toNode(*toGraphCtx) (*grammar.Node, []*grammar.Node) {
+	n := &grammar.Node{Terminal: true, Tsym: `ε`}
+	return n, []*grammar.Node{n}
+}
+
+// matchOnly decodes sym and discards its value, contributing no
+// children - used for the terminals in a FromGraph production, which
+// are matched but, having no generic conversion to T, aren't recorded.
+type matchOnly[T any] struct {
+	sym Symbol[rune]
+}
+
+func (m *matchOnly[T]) Decode(r Reader) ([]T, error) {
+	if _, err := m.sym.Decode(r); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+func (m *matchOnly[T]) toNode(ctx *toGraphCtx) (*grammar.Node, []*grammar.Node) {
+	return m.sym.(graphNode).toNode(ctx)
+}
+
+// singleValue decodes sym and wraps its value as the lone entry of a
+// one-element children slice.
+type singleValue[T any] struct {
+	sym Symbol[T]
+}
+
+func (s *singleValue[T]) Decode(r Reader) ([]T, error) {
+	v, err := s.sym.Decode(r)
+	if err != nil {
+		return nil, err
+	}
+	return []T{v}, nil
+}
+
+func (s *singleValue[T]) toNode(ctx *toGraphCtx) (*grammar.Node, []*grammar.Node) {
+	return s.sym.(graphNode).toNode(ctx)
+}
+
+// concatList decodes a then b in turn, appending b's children to a's.
+type concatList[T any] struct {
+	a, b Symbol[[]T]
+}
+
+func (c *concatList[T]) Decode(r Reader) ([]T, error) {
+	av, err := c.a.Decode(r)
+	if err != nil {
+		return nil, err
+	}
+	bv, err := c.b.Decode(r)
+	if err != nil {
+		return nil, err
+	}
+	return append(av, bv...), nil
+}
+
+func (c *concatList[T]) toNode(ctx *toGraphCtx) (*grammar.Node, []*grammar.Node) {
+	agn, ok := c.a.(graphNode)
+	if !ok {
+		ctx.err = fmt.Errorf("combinator: ToGraph: internal concatList operand missing toNode")
+		return nil, nil
+	}
+	bgn, ok := c.b.(graphNode)
+	if !ok {
+		ctx.err = fmt.Errorf("combinator: ToGraph: internal concatList operand missing toNode")
+		return nil, nil
+	}
+	first, tails := agn.toNode(ctx)
+	bFirst, bTails := bgn.toNode(ctx)
+	for _, t := range tails {
+		t.Suc = bFirst
+	}
+	return first, bTails
+}
+
+// flattenList decodes sym, a list of children-lists produced by one
+// iteration each of a ZeroOrMore loop body, and flattens it into a
+// single children list.
+type flattenList[T any] struct {
+	sym Symbol[[][]T]
+}
+
+func (f *flattenList[T]) Decode(r Reader) ([]T, error) {
+	groups, err := f.sym.Decode(r)
+	if err != nil {
+		return nil, err
+	}
+	var out []T
+	for _, g := range groups {
+		out = append(out, g...)
+	}
+	return out, nil
+}
+
+func (f *flattenList[T]) toNode(ctx *toGraphCtx) (*grammar.Node, []*grammar.Node) {
+	return f.sym.(graphNode).toNode(ctx)
+}
+
+// reduceSymbol decodes seq's children and folds them into a T via
+// action, or, absent one, by returning the single child if there is
+// exactly one and the zero value of T otherwise.
+type reduceSymbol[T any] struct {
+	seq    Symbol[[]T]
+	action func([]T) T
+}
+
+func (s *reduceSymbol[T]) Decode(r Reader) (T, error) {
+	children, err := s.seq.Decode(r)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	if s.action != nil {
+		return s.action(children), nil
+	}
+	var zero T
+	if len(children) == 1 {
+		return children[0], nil
+	}
+	return zero, nil
+}
+
+func (s *reduceSymbol[T]) toNode(ctx *toGraphCtx) (*grammar.Node, []*grammar.Node) {
+	return s.seq.(graphNode).toNode(ctx)
+}
@@ -0,0 +1,86 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package combinator
+
+import (
+	"testing"
+
+	"github.com/mdhender/fluffy-giggle/grammar"
+)
+
+// Regression test for FromGraph's buildSequence, which used to check
+// node.Alt before checking for a loop: a `[...]` loop's entry always
+// carries a non-nil Alt (its own synthetic epsilon exit), so buildChoice
+// treated it as an ordinary sibling alternative instead of handing it to
+// buildLoop, dropping whatever follows the loop on the zero-iterations
+// path - the same bug class gen.emitSequence had.
+func TestFromGraph_LoopThenTerminal(t *testing.T) {
+	_, start, err := grammar.Parse([]byte(`S=a[b]c.`))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	sym, err := FromGraph[string](start, nil)
+	if err != nil {
+		t.Fatalf("FromGraph: %v", err)
+	}
+
+	for _, input := range []string{"ac", "abc", "abbbbc"} {
+		r := NewReader(input)
+		if _, err := sym.Decode(r); err != nil {
+			t.Errorf("Decode(%q): %v", input, err)
+			continue
+		}
+		if _, ok := r.Peek(); ok {
+			t.Errorf("Decode(%q): input left unconsumed", input)
+		}
+	}
+
+	for _, input := range []string{"ab", "c", "abbc d"} {
+		r := NewReader(input)
+		if _, err := sym.Decode(r); err == nil {
+			if _, ok := r.Peek(); !ok {
+				t.Errorf("Decode(%q): expected an error or leftover input, got neither", input)
+			}
+		}
+	}
+}
+
+// Regression test for a loop nested inside another loop,
+// buildLoop's same entry-node conflation as gen's emitLoop: the inner
+// loop's own epsilon exit is epsilon-tagged but its Suc (the
+// mandatory 'b') is real content, so filtering every epsilon node out
+// of entry's Alt chain dropped the 'b' and the outer repetition,
+// leaving a Symbol that decoded zero-or-more 'a's and stopped there
+// with err == nil, no matter what followed.
+func TestFromGraph_NestedLoop(t *testing.T) {
+	_, start, err := grammar.Parse([]byte(`S=[[a]b].`))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	sym, err := FromGraph[string](start, nil)
+	if err != nil {
+		t.Fatalf("FromGraph: %v", err)
+	}
+
+	for _, input := range []string{"", "b", "ab", "aab"} {
+		r := NewReader(input)
+		if _, err := sym.Decode(r); err != nil {
+			t.Errorf("Decode(%q): %v", input, err)
+			continue
+		}
+		if _, ok := r.Peek(); ok {
+			t.Errorf("Decode(%q): input left unconsumed", input)
+		}
+	}
+
+	for _, input := range []string{"c", "abc"} {
+		r := NewReader(input)
+		if _, err := sym.Decode(r); err == nil {
+			if _, ok := r.Peek(); !ok {
+				t.Errorf("Decode(%q): expected an error or leftover input, got neither", input)
+			}
+		}
+	}
+}
@@ -0,0 +1,356 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+// Package gen emits a self-contained, goyacc-style Go parser from a
+// grammar built in this module's Node/Header form. Where goyacc turns
+// an LALR grammar into a table-driven shift-reduce parser, gen turns
+// the LL grammars this module targets into ordinary recursive-descent
+// Go source: one function per non-terminal, a switch over the current
+// token driven by precomputed FIRST sets, and a Lexer interface that
+// mirrors goyacc's yyLexer convention.
+//
+// Scope: the generated functions support concatenation, alternation,
+// and `[...]` zero-or-more loops built using the `s.Suc = s; s.Alt =
+// empty` idiom documented in the grammar package - i.e. exactly the
+// shapes this module's own EBNF frontend and hand-built examples
+// produce. A loop whose body itself loops at more than one node deep
+// (a back edge that doesn't return directly to the loop's own entry
+// node) is outside that scope and is reported as an error rather than
+// silently mis-generated.
+package gen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io"
+	"sort"
+	"strconv"
+
+	"github.com/mdhender/fluffy-giggle/grammar"
+	"github.com/mdhender/fluffy-giggle/ll1"
+)
+
+// Generate writes a Go source file implementing a recursive-descent
+// parser for the grammar rooted at start to w. pkgName names the
+// generated package; prefix, if non-empty, is prepended to every
+// exported identifier (Parse, Lexer, Hooks) so multiple generated
+// parsers can coexist in one package, and inserted after "parse" in
+// every unexported per-non-terminal helper's name (parse<prefix><Sym>)
+// so their names don't collide either.
+func Generate(w io.Writer, start *grammar.Header, pkgName, prefix string) error {
+	if start == nil {
+		return fmt.Errorf("gen: nil start symbol")
+	}
+
+	// The generated switches assume a single token of lookahead always
+	// picks at most one alternative; a grammar that isn't LL(1) would
+	// make that assumption false, so refuse to generate code for it
+	// rather than silently emitting a parser that guesses wrong.
+	if _, conflicts, err := ll1.BuildLL1(start); err != nil {
+		return fmt.Errorf("gen: %w", err)
+	} else if len(conflicts) > 0 {
+		var msgs []string
+		for _, c := range conflicts {
+			msgs = append(msgs, c.Error())
+		}
+		return fmt.Errorf("gen: grammar is not LL(1), cannot generate a predictive parser: %v", msgs)
+	}
+
+	g := &generator{prefix: prefix}
+	headers := grammar.ReachableHeaders(start)
+	sort.Slice(headers, func(i, j int) bool { return headers[i].Sym < headers[j].Sym })
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by gen; DO NOT EDIT.\n\n")
+	fmt.Fprintf(&buf, "package %s\n\n", pkgName)
+	fmt.Fprintf(&buf, "import (\n\t\"fmt\"\n\n\t\"github.com/mdhender/fluffy-giggle/ast\"\n)\n\n")
+
+	fmt.Fprintf(&buf, "// %sLexer mirrors goyacc's yyLexer convention: Next returns the next\n", prefix)
+	fmt.Fprintf(&buf, "// token, with ast.Token{Sym: \"$\"} signaling end of input.\n")
+	fmt.Fprintf(&buf, "type %sLexer interface {\n\tNext() ast.Token\n}\n\n", prefix)
+
+	fmt.Fprintf(&buf, "// %sHooks holds optional semantic-action hooks, one per non-terminal,\n", prefix)
+	fmt.Fprintf(&buf, "// invoked with the freshly built node for that production just before\n")
+	fmt.Fprintf(&buf, "// it is returned to its caller. A nil hook is skipped.\n")
+	fmt.Fprintf(&buf, "var %sHooks struct {\n", prefix)
+	for _, h := range headers {
+		fmt.Fprintf(&buf, "\t%s func(*ast.Node)\n", h.Sym)
+	}
+	fmt.Fprintf(&buf, "}\n\n")
+
+	fmt.Fprintf(&buf, "// %sParse parses tokens from lex as the %s grammar and returns the\n", prefix, start.Sym)
+	fmt.Fprintf(&buf, "// resulting parse tree.\n")
+	fmt.Fprintf(&buf, "func %sParse(lex %sLexer) (ast.Node, error) {\n", prefix, prefix)
+	fmt.Fprintf(&buf, "\ttok := lex.Next()\n")
+	fmt.Fprintf(&buf, "\tnode, tok, err := parse%s%s(lex, tok)\n", prefix, start.Sym)
+	fmt.Fprintf(&buf, "\tif err != nil {\n\t\treturn ast.Node{}, err\n\t}\n")
+	fmt.Fprintf(&buf, "\tif tok.Sym != \"$\" {\n\t\treturn ast.Node{}, fmt.Errorf(\"unexpected trailing token %%q\", tok.Sym)\n\t}\n")
+	fmt.Fprintf(&buf, "\treturn *node, nil\n}\n\n")
+
+	for _, h := range headers {
+		if err := g.emitHeader(&buf, h); err != nil {
+			return err
+		}
+	}
+
+	formatted, err := format.Source(buf.Bytes())
+	if err != nil {
+		// Emit the unformatted source anyway so the caller can see
+		// what gen produced while debugging the generator itself.
+		_, _ = w.Write(buf.Bytes())
+		return fmt.Errorf("gen: formatting generated source: %w", err)
+	}
+	_, err = w.Write(formatted)
+	return err
+}
+
+type generator struct {
+	prefix string
+	sym    string // the non-terminal currently being emitted, for error messages
+}
+
+func (g *generator) emitHeader(buf *bytes.Buffer, h *grammar.Header) error {
+	g.sym = h.Sym
+
+	fmt.Fprintf(buf, "func parse%s%s(lex %sLexer, tok ast.Token) (*ast.Node, ast.Token, error) {\n", g.prefix, h.Sym, g.prefix)
+	fmt.Fprintf(buf, "\tnode := &ast.Node{Sym: %q}\n", h.Sym)
+	fmt.Fprintf(buf, "\tvar err error\n\tvar child *ast.Node\n\t_, _ = err, child\n")
+
+	if err := g.emitSequence(buf, h.Entry, nil); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(buf, "\tif hook := %sHooks.%s; hook != nil {\n\t\thook(node)\n\t}\n", g.prefix, h.Sym)
+	fmt.Fprintf(buf, "\treturn node, tok, nil\n}\n\n")
+	return nil
+}
+
+// emitSequence emits code to parse everything starting at node, up to
+// (but not including) loopback, which marks the node that closes one
+// iteration of an enclosing loop. A nil node, or reaching loopback,
+// ends the sequence. The loop check comes first: a `[...]` loop's
+// entry also carries a non-nil Alt (its own epsilon exit), which
+// emitChoice would otherwise mistake for a sibling alternative of some
+// enclosing choice, dropping whatever follows the loop on the
+// zero-iterations path. Past that, node.Alt, if any, means node is
+// itself a choice point among sibling alternatives (handled by
+// emitChoice); otherwise node is a single step in the current
+// alternative's chain.
+func (g *generator) emitSequence(buf *bytes.Buffer, node, loopback *grammar.Node) error {
+	if node == nil || node == loopback {
+		return nil
+	}
+	if grammar.HasBackEdgeToSelf(node) {
+		return g.emitLoop(buf, node, loopback)
+	}
+	if node.Alt != nil {
+		return g.emitChoice(buf, node, loopback)
+	}
+	return g.emitAlternative(buf, node, loopback)
+}
+
+// emitAlternative emits one alternative's own chain: a loop if node
+// starts one, otherwise a single step followed by whatever comes next.
+// Unlike emitSequence, it never reinterprets node itself as a choice
+// point, since any Alt on it belongs to the caller that chose it.
+func (g *generator) emitAlternative(buf *bytes.Buffer, node, loopback *grammar.Node) error {
+	if node == nil || node == loopback {
+		return nil
+	}
+	if grammar.HasBackEdgeToSelf(node) {
+		return g.emitLoop(buf, node, loopback)
+	}
+	g.emitStep(buf, node)
+	return g.emitSequence(buf, node.Suc, loopback)
+}
+
+// emitLoop emits a `[...]` zero-or-more loop headed by entry, then
+// continues the enclosing sequence from whatever follows the loop.
+// The `for` condition already implements "zero or more" by checking
+// FIRST of the body, so the body emitted inside the braces is just
+// one pass through entry's real alternative(s) - the last node of
+// entry's Alt chain is always the epsilon alternative the `[...]`
+// construction appends, the "zero occurrences" exit, and isn't itself
+// part of what repeats. Everything before it is real, even a node
+// that is itself epsilon-tagged: that happens when entry's body is
+// itself a nested loop, whose own epsilon exit still has a non-nil
+// Suc (the content required after that inner loop stops), so it must
+// be walked rather than discarded the way the true trailing exit is.
+func (g *generator) emitLoop(buf *bytes.Buffer, entry *grammar.Node, loopback *grammar.Node) error {
+	var reals []*grammar.Node
+	exit := entry
+	for exit.Alt != nil {
+		reals = append(reals, exit)
+		exit = exit.Alt
+	}
+	if len(reals) == 0 {
+		return fmt.Errorf("gen: %s: loop has no non-epsilon alternative", g.sym)
+	}
+
+	// entry.First() would also pick up whatever follows the loop: the
+	// epsilon exit node it unions in is nullable, so First folds in its
+	// Suc (the continuation after the loop) too. The for condition must
+	// only admit tokens that start another iteration of the body, so
+	// union FIRST over just the real alternatives instead.
+	litSet := map[string]bool{}
+	for _, alt := range reals {
+		for _, sym := range nonEpsilonFirstOfAlternative(alt) {
+			litSet[sym] = true
+		}
+	}
+	var lits []string
+	for sym := range litSet {
+		lits = append(lits, sym)
+	}
+	sort.Strings(lits)
+	fmt.Fprintf(buf, "\tfor %s {\n", tokInSet(lits))
+	if len(reals) == 1 {
+		if err := g.emitLoopAlternative(buf, reals[0], entry); err != nil {
+			return err
+		}
+	} else {
+		fmt.Fprintf(buf, "\tswitch tok.Sym {\n")
+		for _, alt := range reals {
+			altLits := nonEpsilonFirstOfAlternative(alt)
+			if len(altLits) == 0 {
+				return fmt.Errorf("gen: %s: alternative has no first symbols", g.sym)
+			}
+			fmt.Fprintf(buf, "\tcase %s:\n", caseList(altLits))
+			if err := g.emitLoopAlternative(buf, alt, entry); err != nil {
+				return err
+			}
+		}
+		fmt.Fprintf(buf, "\t}\n")
+	}
+	fmt.Fprintf(buf, "\t}\n")
+
+	// exit is the last node of entry's Alt chain: the epsilon
+	// alternative appended when the loop was built. Whatever follows
+	// the loop was linked to its Suc.
+	return g.emitSequence(buf, exit.Suc, loopback)
+}
+
+// emitLoopAlternative emits one pass through a loop body starting at
+// node, stopping exactly at entry (where the body's own Suc chain
+// cycles back to repeat) rather than consulting
+// grammar.HasBackEdgeToSelf again: every node inside the cycle trivially "has a back edge to
+// itself" once you're already inside it, so that check can only be
+// used to discover a loop from the outside, not to walk one from in.
+// A node here can itself be a nested loop's own epsilon exit - it
+// consumes nothing, but its Suc still must be walked, the same as
+// emitStep would be wrong to try to match tok.Sym against "ε".
+// Reaching a node a second time before entry means the body holds a
+// loop whose back edge isn't to entry; that is reported as an error.
+func (g *generator) emitLoopAlternative(buf *bytes.Buffer, node, entry *grammar.Node) error {
+	seen := map[*grammar.Node]bool{}
+	for node != nil {
+		if seen[node] {
+			return fmt.Errorf("gen: %s: loop nested inside a loop body below its entry is not supported", g.sym)
+		}
+		seen[node] = true
+		if !(node.Terminal && node.Tsym == `ε`) {
+			g.emitStep(buf, node)
+		}
+		if node.Suc == entry {
+			return nil
+		}
+		node = node.Suc
+	}
+	return nil
+}
+
+// emitChoice emits a switch over tok.Sym choosing among node's
+// alternatives (node itself and its Alt chain), each walked up to
+// loopback.
+func (g *generator) emitChoice(buf *bytes.Buffer, node *grammar.Node, loopback *grammar.Node) error {
+	var alts []*grammar.Node
+	hasEpsilon := false
+	for n := node; n != nil; n = n.Alt {
+		if n.Terminal && n.Tsym == `ε` {
+			hasEpsilon = true
+			continue
+		}
+		alts = append(alts, n)
+	}
+
+	fmt.Fprintf(buf, "\tswitch tok.Sym {\n")
+	for _, alt := range alts {
+		lits := nonEpsilonFirstOfAlternative(alt)
+		if len(lits) == 0 {
+			return fmt.Errorf("gen: %s: alternative has no first symbols", g.sym)
+		}
+		fmt.Fprintf(buf, "\tcase %s:\n", caseList(lits))
+		if err := g.emitAlternative(buf, alt, loopback); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(buf, "\tdefault:\n")
+	if hasEpsilon {
+		fmt.Fprintf(buf, "\t\t// matches ε: nothing to consume\n")
+	} else {
+		fmt.Fprintf(buf, "\t\treturn nil, tok, fmt.Errorf(\"%s: unexpected token %%q\", tok.Sym)\n", g.sym)
+	}
+	fmt.Fprintf(buf, "\t}\n")
+	return nil
+}
+
+// emitStep emits code to match a single terminal or call a single
+// non-terminal's parse function, advancing tok.
+func (g *generator) emitStep(buf *bytes.Buffer, n *grammar.Node) {
+	if n.Terminal {
+		errMsg := strconv.Quote(fmt.Sprintf("%s: expected %s, got %%q", g.sym, n.Tsym))
+		fmt.Fprintf(buf, "\tif tok.Sym != %s {\n", strconv.Quote(n.Tsym))
+		fmt.Fprintf(buf, "\t\treturn nil, tok, fmt.Errorf(%s, tok.Sym)\n", errMsg)
+		fmt.Fprintf(buf, "\t}\n")
+		fmt.Fprintf(buf, "\tnode.Children = append(node.Children, &ast.Node{Sym: tok.Sym, Tok: tok})\n")
+		fmt.Fprintf(buf, "\ttok = lex.Next()\n")
+		return
+	}
+
+	fmt.Fprintf(buf, "\tchild, tok, err = parse%s%s(lex, tok)\n", g.prefix, n.Nsym.Sym)
+	fmt.Fprintf(buf, "\tif err != nil {\n\t\treturn nil, tok, err\n\t}\n")
+	fmt.Fprintf(buf, "\tnode.Children = append(node.Children, child)\n")
+}
+
+// nonEpsilonFirstOfAlternative returns the sorted FIRST set of node
+// alone, excluding ε, without folding in node.Alt (node's siblings in
+// a choice) or - since a loop's trailing epsilon exit is nullable -
+// whatever follows the loop. Every lookahead decision in this package,
+// including a loop's own `for` condition, is a union of this over the
+// relevant alternatives, never grammar.Node.First directly.
+func nonEpsilonFirstOfAlternative(node *grammar.Node) []string {
+	var lits []string
+	for sym := range node.FirstOfAlternative(map[*grammar.Node]bool{}) {
+		if sym == `ε` {
+			continue
+		}
+		lits = append(lits, sym)
+	}
+	sort.Strings(lits)
+	return lits
+}
+
+func caseList(lits []string) string {
+	var buf bytes.Buffer
+	for i, lit := range lits {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		fmt.Fprintf(&buf, "%q", lit)
+	}
+	return buf.String()
+}
+
+func tokInSet(lits []string) string {
+	var buf bytes.Buffer
+	for i, lit := range lits {
+		if i > 0 {
+			buf.WriteString(" || ")
+		}
+		fmt.Fprintf(&buf, "tok.Sym == %q", lit)
+	}
+	if buf.Len() == 0 {
+		return "false"
+	}
+	return buf.String()
+}
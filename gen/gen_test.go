@@ -0,0 +1,230 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package gen
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/mdhender/fluffy-giggle/grammar"
+)
+
+// Regression test for a loop followed by a terminal, `S ::= 'a' {
+// 'b' } 'c'`: emitLoop used to compute its FIRST set (and its `for`
+// condition) over the whole Alt chain instead of just the loop's own
+// alternative, and emitSequence used to mistake the loop's entry node
+// (which always carries a non-nil Alt to its synthetic epsilon exit)
+// for an ordinary choice point. Together these made the generated
+// parser reject every input, including the zero-iterations case. This
+// test actually compiles and runs the generated code against a Lexer,
+// rather than only checking that gen produced syntactically valid Go,
+// since that's exactly the gap that let both bugs ship.
+func TestGenerate_LoopThenTerminal(t *testing.T) {
+	_, start, err := grammar.Parse([]byte(`S=a[b]c.`))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := Generate(&buf, start, "genparsetest", ""); err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+
+	dir := t.TempDir()
+	moduleRoot := repoRoot(t)
+
+	parserDir := filepath.Join(dir, "genparsetest")
+	if err := os.Mkdir(parserDir, 0755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(parserDir, "parser.go"), buf.Bytes(), 0644); err != nil {
+		t.Fatalf("write parser.go: %v", err)
+	}
+
+	goMod := fmt.Sprintf("module genparsetest\n\ngo 1.21\n\nrequire github.com/mdhender/fluffy-giggle v0.0.0\n\nreplace github.com/mdhender/fluffy-giggle => %s\n", moduleRoot)
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte(goMod), 0644); err != nil {
+		t.Fatalf("write go.mod: %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte(driverSource), 0644); err != nil {
+		t.Fatalf("write main.go: %v", err)
+	}
+
+	cases := []struct {
+		toks    string // space-separated token symbols
+		wantErr bool
+	}{
+		{"a c", false},         // zero iterations of the loop
+		{"a b c", false},       // one iteration
+		{"a b b b b c", false}, // several iterations
+		{"a b", true},          // missing the trailing 'c'
+		{"c", true},            // missing the required leading 'a'
+	}
+
+	for _, tc := range cases {
+		cmd := exec.Command("go", "run", ".", tc.toks)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), "GOFLAGS=-mod=mod", "GOPROXY=off")
+		out, err := cmd.CombinedOutput()
+		gotErr := err != nil
+		if gotErr != tc.wantErr {
+			t.Errorf("tokens %q: run error = %v (output %q), wantErr %v", tc.toks, err, out, tc.wantErr)
+		}
+	}
+}
+
+// Regression test for a loop nested inside another loop, `S ::= { {
+// 'a' } 'b' }`: emitLoop used to filter every epsilon-tagged node out
+// of entry's Alt chain, but the inner loop's own epsilon exit is
+// epsilon-tagged too even though its Suc (the mandatory 'b') is real
+// content - filtering it out along with the outer loop's true bypass
+// dropped both the 'b' and the outer repetition entirely, so the
+// generated parser only ever matched a run of 'a's. As with
+// TestGenerate_LoopThenTerminal, this compiles and runs the generated
+// code rather than just checking it's syntactically valid Go.
+func TestGenerate_NestedLoop(t *testing.T) {
+	_, start, err := grammar.Parse([]byte(`S=[[a]b].`))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := Generate(&buf, start, "nestedlooptest", ""); err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+
+	dir := t.TempDir()
+	moduleRoot := repoRoot(t)
+
+	parserDir := filepath.Join(dir, "nestedlooptest")
+	if err := os.Mkdir(parserDir, 0755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(parserDir, "parser.go"), buf.Bytes(), 0644); err != nil {
+		t.Fatalf("write parser.go: %v", err)
+	}
+
+	goMod := fmt.Sprintf("module nestedlooptest\n\ngo 1.21\n\nrequire github.com/mdhender/fluffy-giggle v0.0.0\n\nreplace github.com/mdhender/fluffy-giggle => %s\n", moduleRoot)
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte(goMod), 0644); err != nil {
+		t.Fatalf("write go.mod: %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte(nestedLoopDriverSource), 0644); err != nil {
+		t.Fatalf("write main.go: %v", err)
+	}
+
+	cases := []struct {
+		toks    string // space-separated token symbols
+		wantErr bool
+	}{
+		{"", false},      // zero iterations of the outer loop
+		{"b", false},     // one outer iteration, zero inner iterations
+		{"a b", false},   // one outer iteration, one inner iteration
+		{"a a b", false}, // one outer iteration, several inner iterations
+		{"c", true},      // not in the grammar at all
+	}
+
+	for _, tc := range cases {
+		cmd := exec.Command("go", "run", ".", tc.toks)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), "GOFLAGS=-mod=mod", "GOPROXY=off")
+		out, err := cmd.CombinedOutput()
+		gotErr := err != nil
+		if gotErr != tc.wantErr {
+			t.Errorf("tokens %q: run error = %v (output %q), wantErr %v", tc.toks, err, out, tc.wantErr)
+		}
+	}
+}
+
+// nestedLoopDriverSource is driverSource with its import path changed
+// to match TestGenerate_NestedLoop's own module/package name.
+const nestedLoopDriverSource = `package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"nestedlooptest/nestedlooptest"
+
+	"github.com/mdhender/fluffy-giggle/ast"
+)
+
+type sliceLexer struct {
+	toks []string
+	pos  int
+}
+
+func (l *sliceLexer) Next() ast.Token {
+	if l.pos >= len(l.toks) {
+		return ast.Token{Sym: "$"}
+	}
+	sym := l.toks[l.pos]
+	l.pos++
+	return ast.Token{Sym: sym, Lit: sym}
+}
+
+func main() {
+	toks := strings.Fields(os.Args[1])
+	_, err := nestedlooptest.Parse(&sliceLexer{toks: toks})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+`
+
+func repoRoot(t *testing.T) string {
+	t.Helper()
+	_, file, _, ok := runtime.Caller(0)
+	if !ok {
+		t.Fatal("runtime.Caller failed")
+	}
+	return filepath.Dir(filepath.Dir(file))
+}
+
+// driverSource is a minimal Lexer plus a main that parses its
+// command-line argument as a space-separated token stream and reports
+// whether genparsetest.Parse accepted it, exercising the generated
+// code the way a real caller would rather than unit-testing it in
+// isolation.
+const driverSource = `package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"genparsetest/genparsetest"
+
+	"github.com/mdhender/fluffy-giggle/ast"
+)
+
+type sliceLexer struct {
+	toks []string
+	pos  int
+}
+
+func (l *sliceLexer) Next() ast.Token {
+	if l.pos >= len(l.toks) {
+		return ast.Token{Sym: "$"}
+	}
+	sym := l.toks[l.pos]
+	l.pos++
+	return ast.Token{Sym: sym, Lit: sym}
+}
+
+func main() {
+	toks := strings.Fields(os.Args[1])
+	_, err := genparsetest.Parse(&sliceLexer{toks: toks})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+`
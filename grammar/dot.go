@@ -0,0 +1,83 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package grammar
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// DOT writes a Graphviz DOT rendering of h and every Header and Node
+// reachable from it: each Header becomes a subgraph cluster containing
+// its own nodes, a terminal is drawn as a box and a non-terminal
+// reference as an ellipse labeled with the Header it points to, Suc
+// edges are solid, and Alt edges are dashed.
+func (h *Header) DOT(w io.Writer) error {
+	headers := ReachableHeaders(h)
+
+	ids := map[*Node]string{}
+	nextID := 0
+	idFor := func(n *Node) string {
+		if id, ok := ids[n]; ok {
+			return id
+		}
+		id := fmt.Sprintf("n%d", nextID)
+		nextID++
+		ids[n] = id
+		return id
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "digraph grammar {\n\trankdir=LR;\n")
+
+	for i, hdr := range headers {
+		fmt.Fprintf(&buf, "\tsubgraph cluster_%d {\n\t\tlabel=%q;\n", i, hdr.Sym)
+		for _, n := range reachableNodes(hdr.Entry) {
+			id := idFor(n)
+			if n.Terminal {
+				fmt.Fprintf(&buf, "\t\t%s [shape=box, label=%q];\n", id, n.Tsym)
+			} else {
+				fmt.Fprintf(&buf, "\t\t%s [shape=ellipse, label=%q];\n", id, n.Nsym.Sym)
+			}
+		}
+		fmt.Fprintf(&buf, "\t}\n")
+	}
+
+	for _, hdr := range headers {
+		for _, n := range reachableNodes(hdr.Entry) {
+			id := idFor(n)
+			if n.Suc != nil {
+				fmt.Fprintf(&buf, "\t%s -> %s;\n", id, idFor(n.Suc))
+			}
+			if n.Alt != nil {
+				fmt.Fprintf(&buf, "\t%s -> %s [style=dashed];\n", id, idFor(n.Alt))
+			}
+		}
+	}
+
+	fmt.Fprintf(&buf, "}\n")
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// reachableNodes returns every Node reachable from entry by following
+// Suc and Alt, without crossing into another Header's nodes.
+func reachableNodes(entry *Node) []*Node {
+	seen := map[*Node]bool{}
+	var order []*Node
+
+	var visit func(n *Node)
+	visit = func(n *Node) {
+		if n == nil || seen[n] {
+			return
+		}
+		seen[n] = true
+		order = append(order, n)
+		visit(n.Suc)
+		visit(n.Alt)
+	}
+	visit(entry)
+
+	return order
+}
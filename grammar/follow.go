@@ -0,0 +1,101 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package grammar
+
+// endOfInput marks the follow symbol implicitly expected after the
+// start symbol, mirroring the conventional '$' end-of-input marker.
+const endOfInput = "$"
+
+// Follow computes the FOLLOW sets for every non-terminal reachable
+// from start using the standard fixed-point algorithm: for each
+// occurrence of a non-terminal B followed by β in some production
+// A -> ... B β ..., add FIRST(β) \ {ε} to FOLLOW(B); if β is nullable
+// (including the case where β is empty, i.e. B is the last symbol of
+// the production), add FOLLOW(A) to FOLLOW(B) as well. Iteration
+// continues until no FOLLOW set changes.
+func Follow(start *Header) map[*Header]map[string]bool {
+	headers := ReachableHeaders(start)
+
+	follow := map[*Header]map[string]bool{}
+	for _, h := range headers {
+		follow[h] = map[string]bool{}
+	}
+	follow[start][endOfInput] = true
+
+	for changed := true; changed; {
+		changed = false
+		for _, h := range headers {
+			visited := map[*Node]bool{}
+			var walk func(node *Node)
+			walk = func(node *Node) {
+				if node == nil || visited[node] {
+					return
+				}
+				visited[node] = true
+
+				if !node.Terminal && node.Nsym != nil {
+					B := node.Nsym
+					for sym := range node.Suc.First(map[*Node]bool{}) {
+						if sym == `ε` {
+							continue
+						}
+						if !follow[B][sym] {
+							follow[B][sym] = true
+							changed = true
+						}
+					}
+					if node.Suc.Nullable(map[*Node]bool{}) {
+						for sym := range follow[h] {
+							if !follow[B][sym] {
+								follow[B][sym] = true
+								changed = true
+							}
+						}
+					}
+				}
+
+				walk(node.Suc)
+				walk(node.Alt)
+			}
+			walk(h.Entry)
+		}
+	}
+
+	return follow
+}
+
+// ReachableHeaders returns every Header reachable from start, visiting
+// non-terminal references in the order they're first encountered. It
+// is the single shared copy of this traversal; ll1 and gen both call
+// it instead of keeping their own.
+func ReachableHeaders(start *Header) []*Header {
+	seenHeaders := map[*Header]bool{}
+	var order []*Header
+
+	var visitHeader func(h *Header)
+	visitHeader = func(h *Header) {
+		if h == nil || seenHeaders[h] {
+			return
+		}
+		seenHeaders[h] = true
+		order = append(order, h)
+
+		seenNodes := map[*Node]bool{}
+		var visitNode func(node *Node)
+		visitNode = func(node *Node) {
+			if node == nil || seenNodes[node] {
+				return
+			}
+			seenNodes[node] = true
+			if !node.Terminal {
+				visitHeader(node.Nsym)
+			}
+			visitNode(node.Suc)
+			visitNode(node.Alt)
+		}
+		visitNode(h.Entry)
+	}
+	visitHeader(start)
+
+	return order
+}
@@ -0,0 +1,273 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+// Package grammar implements the Node/Header graph representation used
+// throughout this module to model little EBNF-like grammars, along
+// with First, Nullable, and Follow set computations over that graph.
+//
+// a terminal "x" is implemented as
+//
+//	x := &Node{Terminal: true, Tsym: "x"}
+//
+// a non-terminal "A" is implemented as
+//
+//	a := &Header{Sym: "A"}
+//
+// a sequence of nodes S1, S2, S3 is implemented as
+//
+//	s3 := &Node{}
+//	s2 := &Node{Suc: s3}
+//	s1 := &Node{Suc: s2}
+//
+// a choice of nodes (a/k/a "alternates") S1, S2, S3 is implemented as
+//
+//	s3 := &Node{}
+//	s2 := &Node{Alt: s3}
+//	s1 := &Node{Alt: s2}
+//
+// a loop of node (a/k/a "zero or more") S is implemented as
+//
+//	empty := &Node{}
+//	s := &Node{Alt: empty}
+//	s.Suc = s
+package grammar
+
+/*
+ * type
+ *   pointer = ^node;
+ *   node =
+ *		record suc, alt: pointer;
+ *			case terminal: boolean of
+ *				true: (tsym: char);
+ *				false: (nsym: hpointer);
+ *		end;
+ */
+
+type Node struct {
+	// symbol for this node
+	Terminal bool    // Discriminating flag: true for terminal, false for non-terminal
+	Tsym     string  // Terminal symbol (valid if Terminal is true)
+	Nsym     *Header // Non-terminal symbol (valid if Terminal is false)
+
+	Suc *Node // Successor link
+	Alt *Node // Alternative
+}
+
+/*
+ * type
+ * 	  hpointer = ^header;
+ * 	  header =
+ * 		record
+ * 			entry: pointer;  // A pointer to a 'node' record
+ * 			sym: char        // A character symbol
+ * 		end;
+ */
+
+type Header struct {
+	Sym   string // A character symbol
+	Entry *Node  // Pointer to another Node
+}
+
+func (h *Header) First(visited map[*Node]bool) map[string]bool {
+	var firstSymbols = map[string]bool{} // Set of terminal symbols
+	for sym := range h.Entry.First(visited) {
+		firstSymbols[sym] = true
+	}
+	return firstSymbols
+}
+
+// Nullable reports whether this non-terminal can derive the empty
+// string.
+func (h *Header) Nullable(visited map[*Node]bool) bool {
+	if h == nil {
+		return true
+	}
+	return h.Entry.Nullable(visited)
+}
+
+// First computes the set of first symbols for a non-terminal node.
+func (node *Node) First(visited map[*Node]bool) map[string]bool {
+	var firstSymbols = map[string]bool{} // Set of all first terminal symbols
+
+	if node == nil {
+		return firstSymbols
+	}
+
+	// To avoid cycles, check if the node has already been visited
+	if visited[node] {
+		return firstSymbols
+	}
+	visited[node] = true
+
+	stepNullable := false
+	if node.Terminal {
+		// If it's a terminal node, add its symbol to the result
+		firstSymbols[node.Tsym] = true
+		stepNullable = node.Tsym == `ε`
+	} else if node.Nsym != nil && node.Nsym.Entry != nil {
+		// If it's non-terminal, recurse on its Header Entry. If
+		// something real still follows (node.Suc), drop the literal
+		// "ε" this folds in: node.Nsym being nullable doesn't mean
+		// node itself can start with nothing, since node.Suc is still
+		// mandatory.
+		for sym := range node.Nsym.First(visited) {
+			if sym == `ε` && node.Suc != nil {
+				continue
+			}
+			firstSymbols[sym] = true
+		}
+		stepNullable = node.Nsym.Nullable(map[*Node]bool{})
+	}
+
+	// A nullable step doesn't account for everything that can come
+	// first: whatever follows it (Suc) can also appear first.
+	if stepNullable {
+		for sym := range node.Suc.First(visited) {
+			firstSymbols[sym] = true
+		}
+	}
+
+	// Recurse on Alternative (Alt) nodes
+	if node.Alt != nil {
+		for sym := range node.Alt.First(visited) {
+			firstSymbols[sym] = true
+		}
+	}
+
+	return firstSymbols
+}
+
+// FirstOfAlternative computes FIRST for node's own step and its Suc
+// continuation only, unlike First which also unions in whatever
+// node.Alt (node's siblings in a choice) can start with. Call First on
+// a Header's Entry to get FIRST of the whole non-terminal; call
+// FirstOfAlternative on one alternative of a choice when the
+// alternatives must be told apart by lookahead, since each one
+// reporting its siblings' symbols too would make every multi-way
+// choice look ambiguous.
+func (node *Node) FirstOfAlternative(visited map[*Node]bool) map[string]bool {
+	var firstSymbols = map[string]bool{}
+
+	if node == nil {
+		return firstSymbols
+	}
+	if visited[node] {
+		return firstSymbols
+	}
+	visited[node] = true
+
+	stepNullable := false
+	if node.Terminal {
+		firstSymbols[node.Tsym] = true
+		stepNullable = node.Tsym == `ε`
+	} else if node.Nsym != nil && node.Nsym.Entry != nil {
+		// See the identical comment in First: drop the literal "ε"
+		// this folds in when something real still follows.
+		for sym := range node.Nsym.First(visited) {
+			if sym == `ε` && node.Suc != nil {
+				continue
+			}
+			firstSymbols[sym] = true
+		}
+		stepNullable = node.Nsym.Nullable(map[*Node]bool{})
+	}
+
+	if stepNullable {
+		for sym := range node.Suc.First(visited) {
+			firstSymbols[sym] = true
+		}
+	}
+
+	return firstSymbols
+}
+
+// Nullable reports whether the fragment starting at this node (this
+// node's own step, its Suc continuation, and any Alt alternative) can
+// derive the empty string.
+func (node *Node) Nullable(visited map[*Node]bool) bool {
+	if node == nil {
+		return true
+	}
+
+	// A node revisited while computing its own nullability sits on a
+	// cycle; treat it as non-nullable rather than looping forever.
+	if visited[node] {
+		return false
+	}
+	visited[node] = true
+
+	stepNullable := false
+	if node.Terminal {
+		stepNullable = node.Tsym == `ε`
+	} else if node.Nsym != nil {
+		stepNullable = node.Nsym.Nullable(visited)
+	}
+
+	if stepNullable && node.Suc.Nullable(visited) {
+		return true
+	}
+	// Unlike a nil Suc (nothing more required, hence nullable), a nil
+	// Alt just means there are no more alternatives to try here.
+	if node.Alt != nil {
+		return node.Alt.Nullable(visited)
+	}
+	return false
+}
+
+// NullableOfAlternative reports whether node's own step and its Suc
+// continuation can derive the empty string, without also trying
+// node.Alt the way Nullable does - the per-alternative counterpart to
+// FirstOfAlternative, needed for the same reason: an alternative must
+// be judged nullable (or not) on its own, not on whether some sibling
+// later in the Alt chain happens to be nullable.
+func (node *Node) NullableOfAlternative(visited map[*Node]bool) bool {
+	if node == nil {
+		return true
+	}
+	if visited[node] {
+		return false
+	}
+	visited[node] = true
+
+	stepNullable := false
+	if node.Terminal {
+		stepNullable = node.Tsym == `ε`
+	} else if node.Nsym != nil {
+		stepNullable = node.Nsym.Nullable(visited)
+	}
+
+	return stepNullable && node.Suc.Nullable(visited)
+}
+
+// HasBackEdgeToSelf reports whether node is reachable from one of its
+// own descendants by following only Suc/Alt edges (i.e. without
+// crossing into another production through a non-terminal reference).
+// This is exactly the `s.Suc = s` idiom a `[...]` loop is built with;
+// it's the single shared copy of this check, used by gen, combinator,
+// and this package's own Railroad to find a loop in the graph.
+func HasBackEdgeToSelf(node *Node) bool {
+	const (
+		white = iota
+		gray
+		black
+	)
+	color := map[*Node]int{node: gray}
+
+	var visit func(n *Node) bool
+	visit = func(n *Node) bool {
+		if n == nil {
+			return false
+		}
+		if n == node {
+			return true
+		}
+		if color[n] != white {
+			return false
+		}
+		color[n] = gray
+		found := visit(n.Suc) || visit(n.Alt)
+		color[n] = black
+		return found
+	}
+
+	return visit(node.Suc) || visit(node.Alt)
+}
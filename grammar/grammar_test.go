@@ -0,0 +1,154 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package grammar
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestParse_Valid(t *testing.T) {
+	symtab, start, err := Parse([]byte(`S=a[b]c.`))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if start == nil || start.Sym != "S" {
+		t.Fatalf("Parse: start = %v, want header S", start)
+	}
+	if symtab['S'] != start {
+		t.Errorf("Parse: symtab['S'] = %v, want start", symtab['S'])
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	if _, _, err := Parse([]byte(`S=a[b.`)); err == nil {
+		t.Fatal("Parse: expected an error for an unclosed loop, got nil")
+	}
+}
+
+// Regression test: First used to fold the whole FIRST set of a
+// nullable non-terminal - including the literal "ε" - into the
+// result even when something real still followed (node.Suc), so
+// First(S) for `S ::= A 'c'; A ::= { 'a' }` wrongly reported that S
+// could start with nothing.
+func TestFirst_StripsEpsilonWhenSucFollows(t *testing.T) {
+	_, start, err := Parse([]byte(`S=Ac.A=[a].`))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	got := sortedKeys(start.First(map[*Node]bool{}))
+	want := []string{"a", "c"}
+	if !equalStrings(got, want) {
+		t.Errorf("First(S) = %v, want %v", got, want)
+	}
+}
+
+func TestNullable(t *testing.T) {
+	_, start, err := Parse([]byte(`S=[a].`))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if !start.Nullable(map[*Node]bool{}) {
+		t.Errorf("Nullable(S) = false for `[a]`, want true")
+	}
+
+	_, start, err = Parse([]byte(`S=ab.`))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if start.Nullable(map[*Node]bool{}) {
+		t.Errorf("Nullable(S) = true for `ab`, want false")
+	}
+}
+
+func TestFollow(t *testing.T) {
+	symtab, start, err := Parse([]byte(`S=Ac.A=[a].`))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	follow := Follow(start)
+	got := sortedKeys(follow[symtab['A']])
+	want := []string{"c"}
+	if !equalStrings(got, want) {
+		t.Errorf("Follow(A) = %v, want %v", got, want)
+	}
+}
+
+// Smoke test: DOT must not silently drop graph content - every
+// terminal symbol and every non-terminal reference in the grammar
+// should show up as a node label.
+func TestDOT_DoesNotDropContent(t *testing.T) {
+	_, start, err := Parse([]byte(`S=aAb.A=c.`))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := start.DOT(&buf); err != nil {
+		t.Fatalf("DOT: %v", err)
+	}
+	out := buf.String()
+
+	for _, want := range []string{`label="a"`, `label="b"`, `label="c"`, `label="A"`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("DOT output missing %s:\n%s", want, out)
+		}
+	}
+}
+
+// Regression test covering the same bug class fixed in
+// buildLoopAlternativeDiagram: Railroad used to render a loop nested
+// inside another loop byte-for-byte identically to the inner loop
+// alone, silently dropping the mandatory trailing symbol and the
+// outer repetition.
+func TestRailroad_NestedLoopNotDropped(t *testing.T) {
+	_, flat, err := Parse([]byte(`S=[a].`))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	var flatBuf bytes.Buffer
+	if err := flat.Railroad(&flatBuf); err != nil {
+		t.Fatalf("Railroad: %v", err)
+	}
+
+	_, nested, err := Parse([]byte(`S=[[a]b].`))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	var nestedBuf bytes.Buffer
+	if err := nested.Railroad(&nestedBuf); err != nil {
+		t.Fatalf("Railroad: %v", err)
+	}
+
+	if flatBuf.String() == nestedBuf.String() {
+		t.Fatal("Railroad: nested loop rendered identically to the flat loop; the outer loop and trailing symbol were dropped")
+	}
+	if !strings.Contains(nestedBuf.String(), `>b<`) {
+		t.Errorf("Railroad: nested loop's SVG is missing the trailing 'b':\n%s", nestedBuf.String())
+	}
+}
+
+func sortedKeys(m map[string]bool) []string {
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,230 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package grammar
+
+import "fmt"
+
+// Parse reads a grammar written in the little EBNF-like notation
+// (e.g. `A=x,(B).B=AC.C=[+A].`) and builds the Node/Header graph
+// for it. A production has the form `LHS=term.`, where a term is a
+// comma-separated list of alternatives and each alternative (a factor)
+// is a concatenation of primaries. A primary is a terminal character,
+// a non-terminal `A`..`Z`, a `(...)` group, or a `[...]` zero-or-more
+// loop built using the `s.Suc = s; s.Alt = empty` idiom described in
+// the package documentation.
+//
+// It returns the symbol table built while parsing and the *Header for
+// the first production encountered, which callers treat as the start
+// symbol.
+func Parse(input []byte) (map[rune]*Header, *Header, error) {
+	p := newParser(input)
+
+	var start *Header
+	for p.sym != '$' {
+		h, err := p.expr()
+		if err != nil {
+			return nil, nil, err
+		}
+		if start == nil {
+			start = h
+		}
+	}
+	return p.symtab, start, nil
+}
+
+// parser holds the state needed to recursive-descend over the input.
+type parser struct {
+	input []byte
+	pos   int
+	sym   rune
+
+	symtab map[rune]*Header
+}
+
+func newParser(input []byte) *parser {
+	p := &parser{
+		input:  input,
+		symtab: map[rune]*Header{},
+	}
+	p.read()
+	return p
+}
+
+// read advances to the next symbol, skipping whitespace and setting
+// sym to '$' once the input is exhausted.
+func (p *parser) read() {
+	for len(p.input) > 0 && (p.input[0] == ' ' || p.input[0] == '\t' || p.input[0] == '\n' || p.input[0] == '\r') {
+		p.input = p.input[1:]
+		p.pos++
+	}
+	if len(p.input) == 0 {
+		p.sym = '$'
+		return
+	}
+	p.sym = rune(p.input[0])
+	p.input = p.input[1:]
+	p.pos++
+}
+
+// find locates a non-terminal in the symbol table, adding it if it
+// is not already there.
+func (p *parser) find(sym rune) *Header {
+	h, ok := p.symtab[sym]
+	if !ok {
+		h = &Header{Sym: string(sym)}
+		p.symtab[sym] = h
+	}
+	return h
+}
+
+func (p *parser) werror(msg string) error {
+	return fmt.Errorf("column %d: %s", p.pos, msg)
+}
+
+// expr parses a single production `LHS=term.` and stores the result
+// in the symbol table under LHS.
+func (p *parser) expr() (*Header, error) {
+	if !('A' <= p.sym && p.sym <= 'Z') {
+		return nil, p.werror("expected non-terminal")
+	}
+	h := p.find(p.sym)
+	p.read()
+
+	if p.sym != '=' {
+		return nil, p.werror("expected '='")
+	}
+	p.read()
+
+	first, tails, err := p.term()
+	if err != nil {
+		return nil, err
+	}
+	h.Entry = first
+	_ = tails // a production's trailing tails are left dangling; nothing follows the RHS
+
+	if p.sym != '.' {
+		return nil, p.werror("expected '.'")
+	}
+	p.read()
+
+	return h, nil
+}
+
+// term parses a comma-separated list of factors, linking them as
+// alternatives via Alt. It returns the first node of the term and the
+// list of dangling nodes (one per alternative) whose Suc must be
+// patched to whatever follows the term.
+func (p *parser) term() (first *Node, tails []*Node, err error) {
+	first, tails, err = p.factor()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tail := first
+	for p.sym == ',' {
+		p.read()
+
+		nf, nt, err := p.factor()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		for tail.Alt != nil {
+			tail = tail.Alt
+		}
+		tail.Alt = nf
+		tail = nf
+
+		tails = append(tails, nt...)
+	}
+	return first, tails, nil
+}
+
+// factor parses a concatenation of one or more primaries, linking them
+// via Suc. It returns the first node of the factor and the dangling
+// nodes whose Suc must be patched to whatever follows the factor.
+func (p *parser) factor() (first *Node, tails []*Node, err error) {
+	first, tails, err = p.primary()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for p.startsPrimary() {
+		nf, nt, err := p.primary()
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, t := range tails {
+			t.Suc = nf
+		}
+		tails = nt
+	}
+	return first, tails, nil
+}
+
+// startsPrimary reports whether the current symbol can begin a primary.
+func (p *parser) startsPrimary() bool {
+	switch p.sym {
+	case '$', '.', ',', ')', ']':
+		return false
+	default:
+		return true
+	}
+}
+
+// primary parses a terminal character, a non-terminal, a `(...)` group,
+// or a `[...]` zero-or-more loop.
+func (p *parser) primary() (first *Node, tails []*Node, err error) {
+	switch {
+	case 'A' <= p.sym && p.sym <= 'Z':
+		n := &Node{Nsym: p.find(p.sym)}
+		p.read()
+		return n, []*Node{n}, nil
+
+	case p.sym == '(':
+		p.read()
+		first, tails, err = p.term()
+		if err != nil {
+			return nil, nil, err
+		}
+		if p.sym != ')' {
+			return nil, nil, p.werror("expected ')'")
+		}
+		p.read()
+		return first, tails, nil
+
+	case p.sym == '[':
+		p.read()
+		bodyFirst, bodyTails, err := p.term()
+		if err != nil {
+			return nil, nil, err
+		}
+		if p.sym != ']' {
+			return nil, nil, p.werror("expected ']'")
+		}
+		p.read()
+
+		// s.Suc = s; s.Alt = empty: looping back to the entry of the
+		// body on every dangling end, and offering a zero-occurrence
+		// exit via an epsilon alternative appended to the body's
+		// alternation chain.
+		empty := &Node{Terminal: true, Tsym: `ε`}
+		last := bodyFirst
+		for last.Alt != nil {
+			last = last.Alt
+		}
+		last.Alt = empty
+		for _, t := range bodyTails {
+			t.Suc = bodyFirst
+		}
+		return bodyFirst, []*Node{empty}, nil
+
+	case p.sym == '$':
+		return nil, nil, p.werror("unexpected end of input")
+
+	default:
+		n := &Node{Terminal: true, Tsym: string(p.sym)}
+		p.read()
+		return n, []*Node{n}, nil
+	}
+}
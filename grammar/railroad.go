@@ -0,0 +1,370 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package grammar
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// Railroad writes an SVG rendering of h's own production as a
+// Wirth-style syntax diagram: a Suc chain lays its steps out left to
+// right, an Alt chain stacks its branches top to bottom, and the
+// `s.Suc = s; s.Alt = empty` idiom used for a `[...]` group renders as
+// its body on the main line with a loop-back arc drawn beneath it.
+// Railroad only renders h itself - a non-terminal reference is drawn
+// as a labeled box, not expanded in place, so a recursive grammar
+// can't cause unbounded output.
+func (h *Header) Railroad(w io.Writer) error {
+	d, err := buildDiagram(h.Entry, nil)
+	if err != nil {
+		return err
+	}
+
+	const margin = 20
+	const titleHeight = 24
+
+	dw, dh := d.size()
+	totalW, totalH := dw+2*margin, dh+2*margin+titleHeight
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="monospace" font-size="14">`+"\n",
+		totalW, totalH, totalW, totalH)
+	fmt.Fprintf(&buf, `<text x="%d" y="18" font-weight="bold">%s</text>`+"\n", margin, h.Sym)
+	buf.WriteString(d.render(margin, margin+titleHeight))
+	buf.WriteString("</svg>\n")
+
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+// diagram boxes and lays out one piece of a syntax diagram: size
+// reports how much room it needs, and render draws it with its
+// top-left corner at (x, y), returning the SVG markup for it. Every
+// diagram keeps its own "rail" - the horizontal line tokens connect
+// through - at its vertical center, so sequences and choices can line
+// sub-diagrams of different heights up on a common rail.
+type diagram interface {
+	size() (w, h int)
+	render(x, y int) string
+}
+
+const (
+	boxHeight  = 40
+	hGap       = 16
+	vGap       = 10
+	railMargin = 20
+	loopHeight = 30
+)
+
+// emptyDiagram is the "skip this" bypass of a `[...]` group, or the
+// body of a production with nothing left to match: just a line.
+type emptyDiagram struct{}
+
+func (emptyDiagram) size() (int, int) { return 20, boxHeight }
+
+func (emptyDiagram) render(x, y int) string {
+	rail := y + boxHeight/2
+	return fmt.Sprintf(`<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="black"/>`+"\n", x, rail, x+20, rail)
+}
+
+// termBox draws a matched terminal as a rounded box, following the
+// usual railroad-diagram convention of rounded for literals and
+// square for references.
+type termBox struct{ label string }
+
+func (b *termBox) size() (int, int) { return textWidth(b.label), boxHeight }
+
+func (b *termBox) render(x, y int) string {
+	w, h := b.size()
+	return fmt.Sprintf(`<rect x="%d" y="%d" width="%d" height="%d" rx="%d" ry="%d" fill="white" stroke="black"/>`+"\n"+
+		`<text x="%d" y="%d" text-anchor="middle" dominant-baseline="middle">%s</text>`+"\n",
+		x, y, w, h, h/2, h/2, x+w/2, y+h/2, b.label)
+}
+
+// nontermBox draws a reference to another Header as a square box
+// labeled with that Header's symbol.
+type nontermBox struct{ label string }
+
+func (b *nontermBox) size() (int, int) { return textWidth(b.label), boxHeight }
+
+func (b *nontermBox) render(x, y int) string {
+	w, h := b.size()
+	return fmt.Sprintf(`<rect x="%d" y="%d" width="%d" height="%d" fill="white" stroke="black"/>`+"\n"+
+		`<text x="%d" y="%d" text-anchor="middle" dominant-baseline="middle">%s</text>`+"\n",
+		x, y, w, h, x+w/2, y+h/2, b.label)
+}
+
+func textWidth(label string) int {
+	w := len(label)*9 + 30
+	if w < 50 {
+		w = 50
+	}
+	return w
+}
+
+// sequenceDiagram lays its items out left to right on a common rail,
+// joined by short connecting lines.
+type sequenceDiagram struct{ items []diagram }
+
+func (s *sequenceDiagram) size() (int, int) {
+	w, h := 0, boxHeight
+	for i, it := range s.items {
+		iw, ih := it.size()
+		if i > 0 {
+			w += hGap
+		}
+		w += iw
+		if ih > h {
+			h = ih
+		}
+	}
+	return w, h
+}
+
+func (s *sequenceDiagram) render(x, y int) string {
+	_, h := s.size()
+	rail := y + h/2
+
+	var buf bytes.Buffer
+	cx := x
+	for i, it := range s.items {
+		if i > 0 {
+			fmt.Fprintf(&buf, `<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="black"/>`+"\n", cx, rail, cx+hGap, rail)
+			cx += hGap
+		}
+		iw, ih := it.size()
+		buf.WriteString(it.render(cx, rail-ih/2))
+		cx += iw
+	}
+	return buf.String()
+}
+
+// choiceDiagram stacks its items top to bottom, connecting them to a
+// common entry and exit rail with a vertical spine on either side -
+// the usual rendering of a `,` (alternation) in a Wirth diagram.
+type choiceDiagram struct{ items []diagram }
+
+func (c *choiceDiagram) size() (int, int) {
+	w, h := 0, 0
+	for i, it := range c.items {
+		iw, ih := it.size()
+		if iw > w {
+			w = iw
+		}
+		if i > 0 {
+			h += vGap
+		}
+		h += ih
+	}
+	return w + 2*railMargin, h
+}
+
+func (c *choiceDiagram) render(x, y int) string {
+	w, h := c.size()
+	centerY := y + h/2
+	innerW := w - 2*railMargin
+
+	var buf bytes.Buffer
+
+	type placed struct {
+		d    diagram
+		top  int
+		rail int
+	}
+	var items []placed
+	top := y
+	for i, it := range c.items {
+		_, ih := it.size()
+		items = append(items, placed{d: it, top: top, rail: top + ih/2})
+		top += ih
+		if i < len(c.items)-1 {
+			top += vGap
+		}
+	}
+
+	minRail, maxRail := items[0].rail, items[0].rail
+	for _, p := range items {
+		if p.rail < minRail {
+			minRail = p.rail
+		}
+		if p.rail > maxRail {
+			maxRail = p.rail
+		}
+	}
+
+	fmt.Fprintf(&buf, `<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="black"/>`+"\n", x, centerY, x+railMargin, centerY)
+	fmt.Fprintf(&buf, `<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="black"/>`+"\n", x+railMargin, minRail, x+railMargin, maxRail)
+	fmt.Fprintf(&buf, `<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="black"/>`+"\n", x+w-railMargin, centerY, x+w, centerY)
+	fmt.Fprintf(&buf, `<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="black"/>`+"\n", x+w-railMargin, minRail, x+w-railMargin, maxRail)
+
+	for _, p := range items {
+		iw, _ := p.d.size()
+		itemX := x + railMargin + (innerW-iw)/2
+		fmt.Fprintf(&buf, `<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="black"/>`+"\n", x+railMargin, p.rail, itemX, p.rail)
+		buf.WriteString(p.d.render(itemX, p.top))
+		fmt.Fprintf(&buf, `<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="black"/>`+"\n", itemX+iw, p.rail, x+w-railMargin, p.rail)
+	}
+	return buf.String()
+}
+
+// loopDiagram draws body on the main rail with a loop-back arc
+// beneath it and an arrowhead pointing back into the start of body,
+// the standard rendering of the `s.Suc = s; s.Alt = empty` idiom a
+// `[...]` group compiles to.
+type loopDiagram struct{ body diagram }
+
+func (l *loopDiagram) size() (int, int) {
+	bw, bh := l.body.size()
+	return bw, bh + loopHeight
+}
+
+func (l *loopDiagram) render(x, y int) string {
+	bw, bh := l.body.size()
+	// Shift the body down by half the loop's extra height so its rail
+	// sits at this diagram's vertical center.
+	top := y + loopHeight/2
+
+	var buf bytes.Buffer
+	buf.WriteString(l.body.render(x, top))
+
+	rail := top + bh/2
+	arcY := top + bh + loopHeight/4
+	fmt.Fprintf(&buf, `<path d="M %d %d V %d H %d V %d" fill="none" stroke="black"/>`+"\n",
+		x+bw, rail, arcY, x, rail)
+	fmt.Fprintf(&buf, `<polygon points="%d,%d %d,%d %d,%d" fill="black"/>`+"\n",
+		x, rail, x+8, rail-5, x+8, rail+5)
+	return buf.String()
+}
+
+// buildDiagram mirrors gen's emitSequence/emitAlternative/emitChoice
+// and combinator's buildSequence/buildAlternative/buildChoice: it
+// walks the same Node/Header shape, but produces a diagram instead of
+// Go source or a Symbol. The loop check comes before the choice
+// check: a `[...]` loop's entry also carries a non-nil Alt (its own
+// epsilon exit), which buildChoiceDiagram would otherwise mistake for
+// a sibling alternative of some enclosing choice instead of the
+// loop's own bypass.
+func buildDiagram(node, loopback *Node) (diagram, error) {
+	if node == nil || node == loopback {
+		return emptyDiagram{}, nil
+	}
+	if HasBackEdgeToSelf(node) {
+		return buildLoopDiagram(node, loopback)
+	}
+	if node.Alt != nil {
+		return buildChoiceDiagram(node, loopback)
+	}
+	return buildAlternativeDiagram(node, loopback)
+}
+
+func buildAlternativeDiagram(node, loopback *Node) (diagram, error) {
+	if node == nil || node == loopback {
+		return emptyDiagram{}, nil
+	}
+	if HasBackEdgeToSelf(node) {
+		return buildLoopDiagram(node, loopback)
+	}
+	step := buildStepDiagram(node)
+	rest, err := buildDiagram(node.Suc, loopback)
+	if err != nil {
+		return nil, err
+	}
+	return &sequenceDiagram{items: []diagram{step, rest}}, nil
+}
+
+func buildChoiceDiagram(node, loopback *Node) (diagram, error) {
+	var items []diagram
+	for n := node; n != nil; n = n.Alt {
+		d, err := buildAlternativeDiagram(n, loopback)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, d)
+	}
+	return &choiceDiagram{items: items}, nil
+}
+
+// buildLoopDiagram builds the diagram for a `[...]` group: its real
+// alternative(s) become the loop body, since the loop shape itself
+// already provides "zero or more" - the last node of entry's Alt
+// chain is always the epsilon alternative the `[...]` construction
+// appends, the bypass, and isn't itself part of what repeats.
+// Everything before it is real, even a node that is itself
+// epsilon-tagged: that happens when entry's body is itself a nested
+// loop, whose own epsilon exit still has a non-nil Suc (the content
+// required after that inner loop stops), so it must be drawn rather
+// than discarded the way the true trailing bypass is.
+func buildLoopDiagram(entry, loopback *Node) (diagram, error) {
+	var reals []*Node
+	exit := entry
+	for exit.Alt != nil {
+		reals = append(reals, exit)
+		exit = exit.Alt
+	}
+	if len(reals) == 0 {
+		return nil, fmt.Errorf("grammar: Railroad: loop has no non-epsilon alternative")
+	}
+
+	var body diagram
+	if len(reals) == 1 {
+		d, err := buildLoopAlternativeDiagram(reals[0], entry)
+		if err != nil {
+			return nil, err
+		}
+		body = d
+	} else {
+		var items []diagram
+		for _, alt := range reals {
+			d, err := buildLoopAlternativeDiagram(alt, entry)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, d)
+		}
+		body = &choiceDiagram{items: items}
+	}
+
+	// exit is the last node of entry's Alt chain: the epsilon
+	// alternative appended when the loop was built. Whatever follows
+	// the loop was linked to its Suc.
+	rest, err := buildDiagram(exit.Suc, loopback)
+	if err != nil {
+		return nil, err
+	}
+
+	return &sequenceDiagram{items: []diagram{&loopDiagram{body: body}, rest}}, nil
+}
+
+// buildLoopAlternativeDiagram builds one pass through a loop body
+// starting at node, stopping exactly at entry (where the body's own
+// Suc chain cycles back to repeat) rather than consulting
+// HasBackEdgeToSelf again: every node inside the cycle trivially "has
+// a back edge to itself" once you're already inside it, so that check
+// can only be used to discover a loop from the outside, not to walk
+// one from in.
+func buildLoopAlternativeDiagram(node, entry *Node) (diagram, error) {
+	if node == nil {
+		return emptyDiagram{}, nil
+	}
+	step := buildStepDiagram(node)
+	if node.Suc == nil || node.Suc == entry {
+		return step, nil
+	}
+	rest, err := buildLoopAlternativeDiagram(node.Suc, entry)
+	if err != nil {
+		return nil, err
+	}
+	return &sequenceDiagram{items: []diagram{step, rest}}, nil
+}
+
+func buildStepDiagram(n *Node) diagram {
+	if n.Terminal {
+		if n.Tsym == `ε` {
+			return emptyDiagram{}
+		}
+		return &termBox{label: n.Tsym}
+	}
+	return &nontermBox{label: n.Nsym.Sym}
+}
@@ -0,0 +1,151 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+// Package ll1 builds predictive-parser tables from a grammar's
+// Node/Header graph and reports any LL(1) conflicts found along the
+// way. This is the classical "is this grammar LL(1)?" check that
+// Wirth's compiler-construction examples (the origin of the
+// pointer/hpointer/entry/sym type layout the grammar package's
+// comments describe) build toward.
+package ll1
+
+import (
+	"fmt"
+
+	"github.com/mdhender/fluffy-giggle/grammar"
+)
+
+// Conflict describes two alternatives of a non-terminal that cannot be
+// told apart by a single token of lookahead.
+type Conflict struct {
+	Sym       string        // the non-terminal whose alternatives collide
+	Lookahead string        // the lookahead symbol shared by both alternatives
+	A, B      *grammar.Node // the competing alternatives, i.e. the entry node of each factor
+}
+
+func (c Conflict) Error() string {
+	return fmt.Sprintf("%s: alternatives collide on lookahead %q", c.Sym, c.Lookahead)
+}
+
+// Table is a predictive-parser table: for every non-terminal and every
+// lookahead terminal, at most one alternative (a *grammar.Node entry
+// point) may be taken.
+type Table struct {
+	start *grammar.Header
+	// predict[h][lookahead] is the alternative of h to take when the
+	// next token is lookahead.
+	predict map[*grammar.Header]map[string]*grammar.Node
+}
+
+// BuildLL1 computes the predict table for start and reports any
+// conflicts found. The table is still returned when conflicts exist,
+// populated with the first alternative claiming each lookahead, so
+// callers can inspect a best-effort table alongside the diagnostics.
+func BuildLL1(start *grammar.Header) (*Table, []Conflict, error) {
+	if start == nil {
+		return nil, nil, fmt.Errorf("ll1: nil start symbol")
+	}
+
+	headers := grammar.ReachableHeaders(start)
+	follow := grammar.Follow(start)
+
+	table := &Table{
+		start:   start,
+		predict: map[*grammar.Header]map[string]*grammar.Node{},
+	}
+	var conflicts []Conflict
+
+	for _, h := range headers {
+		table.predict[h] = map[string]*grammar.Node{}
+
+		alternatives := alternativesOf(h.Entry)
+		for _, alt := range alternatives {
+			lookaheads := map[string]bool{}
+			for sym := range alt.FirstOfAlternative(map[*grammar.Node]bool{}) {
+				if sym == `ε` {
+					continue
+				}
+				lookaheads[sym] = true
+			}
+			if alt.NullableOfAlternative(map[*grammar.Node]bool{}) {
+				for sym := range follow[h] {
+					lookaheads[sym] = true
+				}
+			}
+
+			for sym := range lookaheads {
+				if existing, ok := table.predict[h][sym]; ok && existing != alt {
+					conflicts = append(conflicts, Conflict{
+						Sym:       h.Sym,
+						Lookahead: sym,
+						A:         existing,
+						B:         alt,
+					})
+					continue
+				}
+				table.predict[h][sym] = alt
+			}
+		}
+	}
+
+	return table, conflicts, nil
+}
+
+// alternativesOf returns the entry node of every alternative of a
+// non-terminal, i.e. the Alt chain starting at entry.
+func alternativesOf(entry *grammar.Node) []*grammar.Node {
+	var alts []*grammar.Node
+	for n := entry; n != nil; n = n.Alt {
+		alts = append(alts, n)
+	}
+	return alts
+}
+
+// Parse runs a standard predictive parser over tokens using an
+// explicit stack of pending graph nodes, consulting the predict table
+// to choose an alternative whenever it reaches a non-terminal.
+func (t *Table) Parse(tokens []string) error {
+	pos := 0
+	lookahead := func() string {
+		if pos < len(tokens) {
+			return tokens[pos]
+		}
+		return "$"
+	}
+
+	// A synthetic node stands in for "parse the start symbol, then
+	// stop" so the loop below can treat it like any other occurrence
+	// of a non-terminal.
+	stack := []*grammar.Node{{Nsym: t.start}}
+
+	for len(stack) > 0 {
+		node := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if node == nil {
+			continue
+		}
+
+		if node.Terminal {
+			if node.Tsym == `ε` {
+				stack = append(stack, node.Suc)
+				continue
+			}
+			if node.Tsym != lookahead() {
+				return fmt.Errorf("ll1: expected %q, got %q", node.Tsym, lookahead())
+			}
+			pos++
+			stack = append(stack, node.Suc)
+			continue
+		}
+
+		alt, ok := t.predict[node.Nsym][lookahead()]
+		if !ok {
+			return fmt.Errorf("ll1: no alternative of %s for lookahead %q", node.Nsym.Sym, lookahead())
+		}
+		stack = append(stack, node.Suc, alt)
+	}
+
+	if pos != len(tokens) {
+		return fmt.Errorf("ll1: unconsumed input starting at %q", tokens[pos])
+	}
+	return nil
+}
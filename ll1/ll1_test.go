@@ -0,0 +1,63 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package ll1
+
+import (
+	"testing"
+
+	"github.com/mdhender/fluffy-giggle/grammar"
+)
+
+// Regression test for a false conflict BuildLL1 used to report on
+// every non-terminal with two or more alternatives: each alternative's
+// lookahead was computed with grammar.Node.First, which unions in the
+// FIRST of every later sibling on the Alt chain too, so "S ::= 'a' |
+// 'b'" reported 'a' and 'b' both colliding on lookahead "b".
+func TestBuildLL1_NoFalseConflictBetweenAlternatives(t *testing.T) {
+	_, start, err := grammar.Parse([]byte(`S=a,b.`))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	table, conflicts, err := BuildLL1(start)
+	if err != nil {
+		t.Fatalf("BuildLL1: %v", err)
+	}
+	if len(conflicts) != 0 {
+		t.Fatalf("BuildLL1: unexpected conflicts: %v", conflicts)
+	}
+
+	for _, tok := range []string{"a", "b"} {
+		if err := table.Parse([]string{tok}); err != nil {
+			t.Errorf("Parse(%q): %v", tok, err)
+		}
+	}
+	if err := table.Parse([]string{"c"}); err == nil {
+		t.Errorf("Parse(%q): expected an error, got nil", "c")
+	}
+}
+
+// A `[...]` loop's body compiles to an Alt chain ending in a synthetic
+// epsilon node (see grammar package doc); alternativesOf sees that
+// epsilon as a sibling "alternative" of the loop body itself, so the
+// body's own alternative must not be judged nullable just because its
+// sibling (the epsilon exit) trivially is - the per-alternative
+// counterpart of the FIRST-pollution bug above. (Table.Parse's stack
+// machine doesn't special-case the loop's self-referencing Suc, so
+// this only checks BuildLL1's own diagnostics, not Parse.)
+func TestBuildLL1_LoopBodyAlternativeIsNotNullable(t *testing.T) {
+	// S ::= A 'c'
+	// A ::= { 'a' }
+	_, start, err := grammar.Parse([]byte(`S=Ac.A=[a].`))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	_, conflicts, err := BuildLL1(start)
+	if err != nil {
+		t.Fatalf("BuildLL1: %v", err)
+	}
+	if len(conflicts) != 0 {
+		t.Fatalf("BuildLL1: unexpected conflicts: %v", conflicts)
+	}
+}